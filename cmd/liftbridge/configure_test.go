@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/liftbridge-io/liftbridge/server"
+)
+
+// Ensure the YAML generated from flags parses back into a Config that
+// matches the flag inputs.
+func TestGenerateConfigYAMLRoundTrip(t *testing.T) {
+	opts, err := parseConfigureFlags([]string{
+		"--cluster-name", "my-cluster",
+		"--nats-servers", "nats://a:4222,nats://b:4222",
+		"--data-dir", "/data/liftbridge",
+		"--bootstrap-seed",
+		"--tls-auto",
+		"--activity-stream",
+	})
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "liftbridge-configure")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	data, err := generateConfigYAML(opts)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "liftbridge.yaml")
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+	config, err := server.NewConfig(path)
+	require.NoError(t, err)
+
+	require.Equal(t, "/data/liftbridge", config.DataDir)
+	require.Equal(t, "my-cluster", config.Clustering.Namespace)
+	require.True(t, config.Clustering.RaftBootstrapSeed)
+	require.True(t, config.Clustering.ServerCert.Enabled)
+	require.Equal(t, []string{"nats://a:4222", "nats://b:4222"}, config.NATS.Servers)
+	require.True(t, config.ActivityStream.Enabled)
+}
+
+// Ensure a flag value containing YAML-significant characters (a colon
+// followed by a space, which would otherwise be read as a new mapping key)
+// round-trips through generateConfigYAML instead of producing a malformed
+// or silently-misparsed config file.
+func TestGenerateConfigYAMLEscapesSpecialCharacters(t *testing.T) {
+	opts, err := parseConfigureFlags([]string{
+		"--cluster-name", "weird: name, with [brackets] and \"quotes\"",
+	})
+	require.NoError(t, err)
+
+	data, err := generateConfigYAML(opts)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "liftbridge-configure")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "liftbridge.yaml")
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+	config, err := server.NewConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, "weird: name, with [brackets] and \"quotes\"", config.Clustering.Namespace)
+}
+
+// Ensure runConfigure refuses to overwrite an existing file without
+// --force, and succeeds with it.
+func TestRunConfigureRefusesOverwriteWithoutForce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "liftbridge-configure")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "liftbridge.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("data.dir: /old\n"), 0644))
+
+	err = runConfigure([]string{"-o", path})
+	require.Error(t, err)
+
+	err = runConfigure([]string{"-o", path, "--force"})
+	require.NoError(t, err)
+
+	config, err := server.NewConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, "/var/lib/liftbridge", config.DataDir)
+}
@@ -0,0 +1,23 @@
+// Command liftbridge provides the `liftbridge configure` subcommand, which
+// generates a starting YAML configuration. It doesn't start a server; any
+// other invocation prints a usage error.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "liftbridge:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) > 0 && args[0] == "configure" {
+		return runConfigure(args[1:])
+	}
+	return fmt.Errorf("usage: liftbridge configure [flags]")
+}
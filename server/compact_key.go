@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy selects the strategy a stream's cleaner goroutine uses to
+// reclaim space: deleting old segments outright, compacting them by
+// deduplicating on a key, or doing both (compacting first, then applying the
+// usual age/size/message-count limits to what's left).
+type RetentionPolicy string
+
+const (
+	// RetentionPolicyDelete removes whole segments once they fall outside
+	// the configured retention limits. This is the traditional Liftbridge
+	// behavior.
+	RetentionPolicyDelete RetentionPolicy = "delete"
+	// RetentionPolicyCompact rewrites segments to keep only the latest
+	// record for each compaction key, similar to Kafka/NATS JetStream log
+	// compaction.
+	RetentionPolicyCompact RetentionPolicy = "compact"
+	// RetentionPolicyCompactThenDelete compacts segments and then applies
+	// the usual retention limits to the compacted result.
+	RetentionPolicyCompactThenDelete RetentionPolicy = "compact-then-delete"
+)
+
+// ParseRetentionPolicy parses the `retention.policy` setting.
+func ParseRetentionPolicy(value string) (RetentionPolicy, error) {
+	switch p := RetentionPolicy(value); p {
+	case RetentionPolicyDelete, RetentionPolicyCompact, RetentionPolicyCompactThenDelete:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown retention policy %q", value)
+	}
+}
+
+// CompactRecord is the minimal view of a stream record a CompactKeyExtractor
+// needs in order to derive the key used for deduplication.
+type CompactRecord interface {
+	// MessageKey returns the key attached to the message, if any.
+	MessageKey() ([]byte, bool)
+	// Header returns the value of the named message header, if present.
+	Header(name string) ([]byte, bool)
+	// Subject returns the NATS subject the message was published to.
+	Subject() string
+	// Timestamp returns when the record was written, used to exempt
+	// recently-written records from compaction via CompactMinCompactionLag.
+	Timestamp() time.Time
+	// Offset returns the record's position in its stream partition. Offsets
+	// are unique and strictly increasing across the whole partition, so
+	// compaction uses them to identify the latest record for a key instead
+	// of comparing CompactRecord values directly: nothing in this interface
+	// requires an implementation to be a comparable type, so a `==` between
+	// two CompactRecords would panic if the concrete type held, say, a slice
+	// field.
+	Offset() int64
+}
+
+// CompactKeyExtractor derives the compaction key for a record. Two records
+// that extract to the same key are deduplicated, with only the newest one
+// retained.
+type CompactKeyExtractor interface {
+	ExtractKey(record CompactRecord) ([]byte, bool)
+}
+
+// messageKeyExtractor uses the message's own key, the default behavior.
+type messageKeyExtractor struct{}
+
+func (messageKeyExtractor) ExtractKey(record CompactRecord) ([]byte, bool) {
+	return record.MessageKey()
+}
+
+// headerKeyExtractor derives the key from a named message header.
+type headerKeyExtractor struct{ name string }
+
+func (h headerKeyExtractor) ExtractKey(record CompactRecord) ([]byte, bool) {
+	return record.Header(h.name)
+}
+
+// subjectTokenKeyExtractor derives the key from a dot-delimited token of the
+// record's subject, e.g. token 1 of "orders.123.created" is "123".
+type subjectTokenKeyExtractor struct{ token int }
+
+func (s subjectTokenKeyExtractor) ExtractKey(record CompactRecord) ([]byte, bool) {
+	tokens := strings.Split(record.Subject(), ".")
+	if s.token < 0 || s.token >= len(tokens) {
+		return nil, false
+	}
+	return []byte(tokens[s.token]), true
+}
+
+// ParseCompactKey parses a `compact.key` setting into a CompactKeyExtractor.
+// Supported forms are "message-key" (the default), "header:<name>", and
+// "subject-token:<n>".
+func ParseCompactKey(spec string) (CompactKeyExtractor, error) {
+	switch {
+	case spec == "" || spec == "message-key":
+		return messageKeyExtractor{}, nil
+	case strings.HasPrefix(spec, "header:"):
+		name := strings.TrimPrefix(spec, "header:")
+		if name == "" {
+			return nil, fmt.Errorf("invalid compact key %q: missing header name", spec)
+		}
+		return headerKeyExtractor{name: name}, nil
+	case strings.HasPrefix(spec, "subject-token:"):
+		raw := strings.TrimPrefix(spec, "subject-token:")
+		token, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compact key %q: %v", spec, err)
+		}
+		return subjectTokenKeyExtractor{token: token}, nil
+	default:
+		return nil, fmt.Errorf("invalid compact key %q", spec)
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCompactRecord struct {
+	key       []byte
+	subject   string
+	headers   map[string][]byte
+	timestamp time.Time
+	offset    int64
+}
+
+func (r *fakeCompactRecord) MessageKey() ([]byte, bool) { return r.key, r.key != nil }
+func (r *fakeCompactRecord) Subject() string            { return r.subject }
+func (r *fakeCompactRecord) Header(name string) ([]byte, bool) {
+	v, ok := r.headers[name]
+	return v, ok
+}
+func (r *fakeCompactRecord) Timestamp() time.Time { return r.timestamp }
+func (r *fakeCompactRecord) Offset() int64        { return r.offset }
+
+// Ensure ParseCompactKey builds the right extractor for each supported spec
+// and rejects anything else.
+func TestParseCompactKey(t *testing.T) {
+	record := &fakeCompactRecord{
+		key:     []byte("msg-key"),
+		subject: "orders.123.created",
+		headers: map[string][]byte{"event-id": []byte("abc")},
+	}
+
+	extractor, err := ParseCompactKey("")
+	require.NoError(t, err)
+	key, ok := extractor.ExtractKey(record)
+	require.True(t, ok)
+	require.Equal(t, []byte("msg-key"), key)
+
+	extractor, err = ParseCompactKey("header:event-id")
+	require.NoError(t, err)
+	key, ok = extractor.ExtractKey(record)
+	require.True(t, ok)
+	require.Equal(t, []byte("abc"), key)
+
+	extractor, err = ParseCompactKey("subject-token:1")
+	require.NoError(t, err)
+	key, ok = extractor.ExtractKey(record)
+	require.True(t, ok)
+	require.Equal(t, []byte("123"), key)
+
+	_, err = ParseCompactKey("subject-token:5")
+	require.NoError(t, err)
+
+	_, err = ParseCompactKey("bogus")
+	require.Error(t, err)
+
+	_, err = ParseCompactKey("header:")
+	require.Error(t, err)
+}
+
+// Ensure ParseRetentionPolicy only accepts the documented values.
+func TestParseRetentionPolicy(t *testing.T) {
+	for _, valid := range []string{"delete", "compact", "compact-then-delete"} {
+		policy, err := ParseRetentionPolicy(valid)
+		require.NoError(t, err)
+		require.Equal(t, RetentionPolicy(valid), policy)
+	}
+	_, err := ParseRetentionPolicy("bogus")
+	require.Error(t, err)
+}
+
+// Ensure EffectiveRetentionPolicy falls back to the legacy Compact bool when
+// no explicit policy is set.
+func TestEffectiveRetentionPolicy(t *testing.T) {
+	s := StreamsConfig{}
+	require.Equal(t, RetentionPolicyDelete, s.EffectiveRetentionPolicy())
+
+	s.Compact = true
+	require.Equal(t, RetentionPolicyCompact, s.EffectiveRetentionPolicy())
+
+	s.RetentionPolicy = RetentionPolicyCompactThenDelete
+	require.Equal(t, RetentionPolicyCompactThenDelete, s.EffectiveRetentionPolicy())
+}
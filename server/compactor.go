@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CompactableSegment is a closed log segment the compactor can rewrite in
+// place, keeping only the newest record for each compaction key.
+type CompactableSegment interface {
+	// Records streams the segment's records in on-disk order.
+	Records(ctx context.Context) (<-chan CompactRecord, error)
+	// Retain rewrites the segment to contain only the records for which
+	// keep returns true.
+	Retain(ctx context.Context, keep func(record CompactRecord) bool) error
+}
+
+// Compactor applies a StreamsConfig's RetentionPolicy/CompactKeyExtractor to
+// a stream's segments, bounded by CompactMaxGoroutines so a stream with many
+// segments can't monopolize the cleaner pool.
+type Compactor struct {
+	config *StreamsConfig
+}
+
+// NewCompactor creates a Compactor for the given stream configuration.
+func NewCompactor(config *StreamsConfig) *Compactor {
+	return &Compactor{config: config}
+}
+
+// Compact runs log compaction across segments, bounded by
+// CompactMaxGoroutines. It's a no-op if the effective retention policy
+// doesn't include compaction. segments must be given oldest-first, matching
+// on-disk order: compaction needs to know the latest occurrence of a key
+// across the whole stream, not just within one segment, before it can
+// safely rewrite any of them.
+func (c *Compactor) Compact(ctx context.Context, segments []CompactableSegment) error {
+	policy := c.config.EffectiveRetentionPolicy()
+	if policy != RetentionPolicyCompact && policy != RetentionPolicyCompactThenDelete {
+		return nil
+	}
+
+	extractor := c.config.CompactKeyExtractor
+	if extractor == nil {
+		extractor = messageKeyExtractor{}
+	}
+
+	latest, err := c.latestByKey(ctx, segments, extractor)
+	if err != nil {
+		return err
+	}
+
+	goroutines := c.config.CompactMaxGoroutines
+	if goroutines <= 0 {
+		goroutines = 1
+	}
+
+	var (
+		sem      = make(chan struct{}, goroutines)
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for _, segment := range segments {
+		segment := segment
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := c.compactSegment(ctx, segment, extractor, latest); err != nil {
+				errOnce.Do(func() { firstErr = err })
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// latestByKey scans every segment, oldest first, to determine the offset of
+// the single latest record for each compaction key across the whole
+// stream. This has to finish before any segment is rewritten: an older
+// segment can only drop a key once it knows whether a newer segment has it
+// too. Offsets, rather than the records themselves, are used to identify
+// the latest occurrence so compactSegment's keep decision doesn't rely on
+// CompactRecord being a comparable type.
+func (c *Compactor) latestByKey(ctx context.Context, segments []CompactableSegment, extractor CompactKeyExtractor) (map[string]int64, error) {
+	latest := make(map[string]int64)
+	for _, segment := range segments {
+		records, err := segment.Records(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for record := range records {
+			key, ok := extractor.ExtractKey(record)
+			if !ok {
+				continue
+			}
+			latest[string(key)] = record.Offset()
+		}
+	}
+	return latest, nil
+}
+
+// compactSegment rewrites a single segment to retain only the records that
+// are either the stream-wide latest occurrence of their compaction key, or
+// younger than CompactMinCompactionLag, which are never compacted away
+// regardless of whether a newer duplicate already exists.
+func (c *Compactor) compactSegment(ctx context.Context, segment CompactableSegment, extractor CompactKeyExtractor, latest map[string]int64) error {
+	minLag := c.config.CompactMinCompactionLag
+	return segment.Retain(ctx, func(record CompactRecord) bool {
+		if minLag > 0 && time.Since(record.Timestamp()) < minLag {
+			return true
+		}
+		key, ok := extractor.ExtractKey(record)
+		if !ok {
+			return true
+		}
+		return latest[string(key)] == record.Offset()
+	})
+}
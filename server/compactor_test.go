@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCompactableSegment is an in-memory CompactableSegment for testing the
+// Compactor's retention decisions without a real commitlog.
+type fakeCompactableSegment struct {
+	records []CompactRecord
+	kept    []CompactRecord
+}
+
+func (s *fakeCompactableSegment) Records(ctx context.Context) (<-chan CompactRecord, error) {
+	ch := make(chan CompactRecord, len(s.records))
+	for _, record := range s.records {
+		ch <- record
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (s *fakeCompactableSegment) Retain(ctx context.Context, keep func(record CompactRecord) bool) error {
+	var kept []CompactRecord
+	for _, record := range s.records {
+		if keep(record) {
+			kept = append(kept, record)
+		}
+	}
+	s.kept = kept
+	return nil
+}
+
+// Ensure Compact dedups a compaction key across segment boundaries,
+// discarding the older duplicate even though it lives in an earlier
+// segment than the one that made it stale.
+func TestCompactDedupsAcrossSegments(t *testing.T) {
+	older := &fakeCompactableSegment{records: []CompactRecord{
+		&fakeCompactRecord{key: []byte("k1"), timestamp: time.Now().Add(-time.Hour), offset: 1},
+	}}
+	newer := &fakeCompactableSegment{records: []CompactRecord{
+		&fakeCompactRecord{key: []byte("k1"), timestamp: time.Now().Add(-time.Minute), offset: 2},
+	}}
+
+	config := &StreamsConfig{RetentionPolicy: RetentionPolicyCompact}
+	compactor := NewCompactor(config)
+	require.NoError(t, compactor.Compact(context.Background(), []CompactableSegment{older, newer}))
+
+	require.Empty(t, older.kept, "older segment's duplicate of k1 should be compacted away")
+	require.Equal(t, newer.records, newer.kept)
+}
+
+// Ensure a record younger than CompactMinCompactionLag is retained even
+// when a newer duplicate of its key already exists.
+func TestCompactHonorsMinCompactionLag(t *testing.T) {
+	older := &fakeCompactableSegment{records: []CompactRecord{
+		&fakeCompactRecord{key: []byte("k1"), timestamp: time.Now(), offset: 1},
+	}}
+	newer := &fakeCompactableSegment{records: []CompactRecord{
+		&fakeCompactRecord{key: []byte("k1"), timestamp: time.Now(), offset: 2},
+	}}
+
+	config := &StreamsConfig{
+		RetentionPolicy:         RetentionPolicyCompact,
+		CompactMinCompactionLag: time.Hour,
+	}
+	compactor := NewCompactor(config)
+	require.NoError(t, compactor.Compact(context.Background(), []CompactableSegment{older, newer}))
+
+	require.Equal(t, older.records, older.kept, "record younger than the min compaction lag must not be compacted away")
+	require.Equal(t, newer.records, newer.kept)
+}
+
+// Ensure Compact is a no-op when the effective retention policy doesn't
+// include compaction.
+func TestCompactNoopWithoutCompactionPolicy(t *testing.T) {
+	segment := &fakeCompactableSegment{records: []CompactRecord{
+		&fakeCompactRecord{key: []byte("k1")},
+	}}
+
+	config := &StreamsConfig{RetentionPolicy: RetentionPolicyDelete}
+	compactor := NewCompactor(config)
+	require.NoError(t, compactor.Compact(context.Background(), []CompactableSegment{segment}))
+
+	require.Nil(t, segment.kept)
+}
@@ -0,0 +1,649 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	client "github.com/liftbridge-io/liftbridge-api/go"
+	"github.com/liftbridge-io/liftbridge/server/protocol"
+)
+
+const (
+	defaultNamespace               = "liftbridge-default"
+	defaultBatchMaxMessages        = 1024
+	defaultBatchMaxTime            = 0
+	defaultMetadataCacheMaxAge     = 2 * time.Minute
+	defaultRaftCacheSize           = 512
+	defaultRaftSnapshots           = 2
+	defaultRaftSnapshotThreshold   = 8192
+	defaultReplicaMaxLagTime       = 15 * time.Second
+	defaultReplicaMaxLeaderTimeout = 15 * time.Second
+	defaultReplicaMaxIdleWait      = 10 * time.Second
+	defaultReplicaFetchTimeout     = 10 * time.Second
+	defaultMinISR                  = 1
+	defaultSegmentMaxBytes         = 268435456 // 256MB
+	defaultRetentionMaxAge         = 7 * 24 * time.Hour
+	defaultCleanerInterval         = 5 * time.Minute
+	defaultCompactMaxGoroutines    = 10
+	defaultServerCertCALifetime    = 365 * 24 * time.Hour
+	defaultServerCertLeafLifetime  = 24 * time.Hour
+	defaultServerCertRotateBefore  = 2 * time.Hour
+)
+
+// HostPort is a simple host/port pair used for the general listen address.
+// The public-facing host/port advertised to clients is held separately in
+// Config.Host and Config.Port.
+type HostPort struct {
+	Host string
+	Port int
+	// HTTP2WriteScheduler selects the frame write scheduler used by the
+	// gRPC API server's HTTP/2 transport listening on this address.
+	HTTP2WriteScheduler HTTP2WriteScheduler
+}
+
+// StreamsConfig contains the broker-wide defaults applied to streams that
+// don't override them with their own per-stream configuration.
+type StreamsConfig struct {
+	RetentionMaxBytes    int64
+	RetentionMaxMessages int64
+	RetentionMaxAge      time.Duration
+	CleanerInterval      time.Duration
+	SegmentMaxBytes      int64
+	SegmentMaxAge        time.Duration
+	Compact              bool
+	CompactMaxGoroutines int
+
+	// RetentionPolicy selects the cleaner strategy. An empty value means no
+	// explicit policy was configured; use EffectiveRetentionPolicy, which
+	// falls back to the legacy Compact bool.
+	RetentionPolicy RetentionPolicy
+	// CompactKey is the raw `compact.key` spec this config was parsed from,
+	// kept around for diffing and logging; CompactKeyExtractor is what the
+	// compactor actually uses.
+	CompactKey              string
+	CompactKeyExtractor     CompactKeyExtractor
+	CompactMinCompactionLag time.Duration
+
+	// StateRecovery selects how a partition reconciles a stale persisted
+	// state snapshot with its on-disk segments at open time.
+	StateRecovery RecoveryMode
+}
+
+// EffectiveRetentionPolicy returns the stream's retention policy, falling
+// back to the legacy Compact bool when RetentionPolicy wasn't explicitly
+// set.
+func (s *StreamsConfig) EffectiveRetentionPolicy() RetentionPolicy {
+	if s.RetentionPolicy != "" {
+		return s.RetentionPolicy
+	}
+	if s.Compact {
+		return RetentionPolicyCompact
+	}
+	return RetentionPolicyDelete
+}
+
+// ParseCustomStreamConfig overlays the non-zero fields of a client-supplied
+// protocol.CustomStreamConfig on top of the receiver, which is expected to
+// already hold the server (or stream-creation-request) defaults. Fields left
+// unset on the custom config retain their existing value. An error is
+// returned if RetentionPolicy or CompactKey is set to an unrecognized value.
+func (s *StreamsConfig) ParseCustomStreamConfig(protoConfig *protocol.CustomStreamConfig) error {
+	if protoConfig == nil {
+		return nil
+	}
+	if protoConfig.RetentionMaxBytes != 0 {
+		s.RetentionMaxBytes = protoConfig.RetentionMaxBytes
+	}
+	if protoConfig.RetentionMaxMessages != 0 {
+		s.RetentionMaxMessages = protoConfig.RetentionMaxMessages
+	}
+	if protoConfig.RetentionMaxAge != 0 {
+		s.RetentionMaxAge = time.Duration(protoConfig.RetentionMaxAge) * time.Millisecond
+	}
+	if protoConfig.CleanerInterval != 0 {
+		s.CleanerInterval = time.Duration(protoConfig.CleanerInterval) * time.Millisecond
+	}
+	if protoConfig.SegmentMaxBytes != 0 {
+		s.SegmentMaxBytes = protoConfig.SegmentMaxBytes
+	}
+	if protoConfig.SegmentMaxAge != 0 {
+		s.SegmentMaxAge = time.Duration(protoConfig.SegmentMaxAge) * time.Millisecond
+	}
+	if protoConfig.CompactMaxGoroutines != 0 {
+		s.CompactMaxGoroutines = int(protoConfig.CompactMaxGoroutines)
+	}
+	// CompactEnabled is tri-state: 0 means the client didn't set it, so the
+	// existing default (or server-level setting) is kept.
+	switch protoConfig.CompactEnabled {
+	case 1:
+		s.Compact = true
+	case 2:
+		s.Compact = false
+	}
+
+	if protoConfig.RetentionPolicy != "" {
+		policy, err := ParseRetentionPolicy(protoConfig.RetentionPolicy)
+		if err != nil {
+			return err
+		}
+		s.RetentionPolicy = policy
+	}
+	if protoConfig.CompactKey != "" {
+		extractor, err := ParseCompactKey(protoConfig.CompactKey)
+		if err != nil {
+			return err
+		}
+		s.CompactKey = protoConfig.CompactKey
+		s.CompactKeyExtractor = extractor
+	}
+	if protoConfig.CompactMinCompactionLag != 0 {
+		s.CompactMinCompactionLag = time.Duration(protoConfig.CompactMinCompactionLag) * time.Millisecond
+	}
+	return nil
+}
+
+// ClusteringConfig contains settings for the Raft-backed metadata cluster.
+type ClusteringConfig struct {
+	ServerID                string
+	Namespace               string
+	RaftSnapshots           int
+	RaftSnapshotThreshold   uint64
+	RaftCacheSize           int
+	RaftBootstrapSeed       bool
+	RaftBootstrapPeers      []string
+	ReplicaMaxLagTime       time.Duration
+	ReplicaMaxLeaderTimeout time.Duration
+	ReplicaMaxIdleWait      time.Duration
+	ReplicaFetchTimeout     time.Duration
+	MinISR                  int
+	ServerCert              ServerCertConfig
+}
+
+// ServerCertConfig controls internally-managed mTLS for inter-broker and
+// Raft traffic. When enabled, the cluster leader mints short-lived server
+// certificates for each peer from a cluster-local CA seeded at bootstrap,
+// instead of requiring operators to provision and rotate certificates by
+// hand.
+type ServerCertConfig struct {
+	Enabled      bool
+	CALifetime   time.Duration
+	LeafLifetime time.Duration
+	RotateBefore time.Duration
+}
+
+// ActivityStreamConfig controls the internal activity stream the server
+// publishes cluster metadata events to.
+type ActivityStreamConfig struct {
+	Enabled          bool
+	PublishTimeout   time.Duration
+	PublishAckPolicy client.AckPolicy
+}
+
+// NATSConfig holds the settings used to connect to the NATS servers backing
+// the cluster.
+type NATSConfig struct {
+	Servers  []string
+	User     string
+	Password string
+}
+
+// Config contains the complete configuration for a Liftbridge server,
+// combining both hardcoded defaults and anything loaded from a YAML file
+// passed to NewConfig.
+type Config struct {
+	Listen              HostPort
+	Host                string
+	Port                int
+	TLSKey              string
+	TLSCert             string
+	LogLevel            uint32
+	LogRecovery         bool
+	LogRaft             bool
+	LogSilent           bool
+	DataDir             string
+	BatchMaxMessages    int
+	BatchMaxTime        time.Duration
+	MetadataCacheMaxAge time.Duration
+	Streams             StreamsConfig
+	Clustering          ClusteringConfig
+	ActivityStream      ActivityStreamConfig
+	NATS                NATSConfig
+
+	reloadMu       sync.Mutex
+	reloadHandlers []func(old, new *Config) error
+}
+
+// OnReload registers a callback that's invoked whenever a running config
+// watcher (see NewConfigWatcher) successfully applies a reloaded
+// configuration. Handlers are invoked in registration order with the
+// previous and new Config; returning an error from a handler fails the
+// reload as a whole and leaves the previous configuration in effect.
+func (c *Config) OnReload(handler func(old, new *Config) error) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	c.reloadHandlers = append(c.reloadHandlers, handler)
+}
+
+func (c *Config) runReloadHandlers(old, new *Config) error {
+	c.reloadMu.Lock()
+	handlers := make([]func(old, new *Config) error, len(c.reloadHandlers))
+	copy(handlers, c.reloadHandlers)
+	c.reloadMu.Unlock()
+
+	for _, handler := range handlers {
+		if err := handler(old, new); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewDefaultConfig returns a Config populated entirely with the server's
+// built-in defaults.
+func NewDefaultConfig() *Config {
+	return &Config{
+		Listen:              HostPort{HTTP2WriteScheduler: HTTP2WriteSchedulerPriority},
+		Host:                "0.0.0.0",
+		Port:                9292,
+		BatchMaxMessages:    defaultBatchMaxMessages,
+		BatchMaxTime:        defaultBatchMaxTime,
+		MetadataCacheMaxAge: defaultMetadataCacheMaxAge,
+		Streams: StreamsConfig{
+			SegmentMaxBytes:      defaultSegmentMaxBytes,
+			RetentionMaxAge:      defaultRetentionMaxAge,
+			CleanerInterval:      defaultCleanerInterval,
+			Compact:              true,
+			CompactMaxGoroutines: defaultCompactMaxGoroutines,
+			StateRecovery:        RecoveryModeStrict,
+		},
+		Clustering: ClusteringConfig{
+			Namespace:               defaultNamespace,
+			RaftCacheSize:           defaultRaftCacheSize,
+			RaftSnapshots:           defaultRaftSnapshots,
+			RaftSnapshotThreshold:   defaultRaftSnapshotThreshold,
+			ReplicaMaxLagTime:       defaultReplicaMaxLagTime,
+			ReplicaMaxLeaderTimeout: defaultReplicaMaxLeaderTimeout,
+			ReplicaMaxIdleWait:      defaultReplicaMaxIdleWait,
+			ReplicaFetchTimeout:     defaultReplicaFetchTimeout,
+			MinISR:                  defaultMinISR,
+			ServerCert: ServerCertConfig{
+				CALifetime:   defaultServerCertCALifetime,
+				LeafLifetime: defaultServerCertLeafLifetime,
+				RotateBefore: defaultServerCertRotateBefore,
+			},
+		},
+		NATS: NATSConfig{
+			Servers: []string{"nats://localhost:4222"},
+		},
+	}
+}
+
+// NewConfig creates a new Config from the given YAML configuration file. If
+// configFile is empty, the default configuration is returned. Any value
+// found in the file overrides the built-in default for that field; fields
+// omitted from the file keep their default. An error is returned if the file
+// can't be read/parsed or contains an unrecognized setting.
+func NewConfig(configFile string) (*Config, error) {
+	config := NewDefaultConfig()
+	if configFile == "" {
+		return config, nil
+	}
+
+	file, err := os.Open(configFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	for k, v := range raw {
+		if err := config.parse(strings.ToLower(k), v); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+func (c *Config) parse(key string, value interface{}) error {
+	switch key {
+	case "listen":
+		hp, err := parseHostPort(value)
+		if err != nil {
+			return errConfig("listen", err)
+		}
+		c.Listen = hp
+	case "host":
+		c.Host = value.(string)
+	case "port":
+		c.Port = value.(int)
+	case "tls-key":
+		c.TLSKey = value.(string)
+	case "tls-cert":
+		c.TLSCert = value.(string)
+	case "log.level":
+		c.LogLevel = uint32(value.(int))
+	case "log.recovery":
+		c.LogRecovery = value.(bool)
+	case "log.raft":
+		c.LogRaft = value.(bool)
+	case "log.silent":
+		c.LogSilent = value.(bool)
+	case "data.dir":
+		c.DataDir = value.(string)
+	case "batch.max.messages":
+		c.BatchMaxMessages = value.(int)
+	case "batch.max.time":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("batch.max.time", err)
+		}
+		c.BatchMaxTime = d
+	case "metadata.cache.max.age":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("metadata.cache.max.age", err)
+		}
+		c.MetadataCacheMaxAge = d
+	case "streams":
+		return parseMap(value, func(k string, v interface{}) error {
+			return c.parseStreams(k, v)
+		})
+	case "clustering":
+		return parseMap(value, func(k string, v interface{}) error {
+			return c.parseClustering(k, v)
+		})
+	case "activitystream":
+		return parseMap(value, func(k string, v interface{}) error {
+			return c.parseActivityStream(k, v)
+		})
+	case "nats":
+		return parseMap(value, func(k string, v interface{}) error {
+			return c.parseNATS(k, v)
+		})
+	default:
+		return fmt.Errorf("unknown configuration setting %q", key)
+	}
+	return nil
+}
+
+func (c *Config) parseStreams(key string, value interface{}) error {
+	switch key {
+	case "retention.max.bytes":
+		c.Streams.RetentionMaxBytes = int64(value.(int))
+	case "retention.max.messages":
+		c.Streams.RetentionMaxMessages = int64(value.(int))
+	case "retention.max.age":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("streams.retention.max.age", err)
+		}
+		c.Streams.RetentionMaxAge = d
+	case "cleaner.interval":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("streams.cleaner.interval", err)
+		}
+		c.Streams.CleanerInterval = d
+	case "segment.max.bytes":
+		c.Streams.SegmentMaxBytes = int64(value.(int))
+	case "segment.max.age":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("streams.segment.max.age", err)
+		}
+		c.Streams.SegmentMaxAge = d
+	case "compact":
+		c.Streams.Compact = value.(bool)
+	case "compact.max.goroutines":
+		c.Streams.CompactMaxGoroutines = value.(int)
+	case "retention.policy":
+		policy, err := ParseRetentionPolicy(value.(string))
+		if err != nil {
+			return errConfig("streams.retention.policy", err)
+		}
+		c.Streams.RetentionPolicy = policy
+	case "compact.key":
+		extractor, err := ParseCompactKey(value.(string))
+		if err != nil {
+			return errConfig("streams.compact.key", err)
+		}
+		c.Streams.CompactKey = value.(string)
+		c.Streams.CompactKeyExtractor = extractor
+	case "compact.min.compaction.lag":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("streams.compact.min.compaction.lag", err)
+		}
+		c.Streams.CompactMinCompactionLag = d
+	case "state.recovery":
+		mode, err := ParseRecoveryMode(value.(string))
+		if err != nil {
+			return errConfig("streams.state.recovery", err)
+		}
+		c.Streams.StateRecovery = mode
+	default:
+		return fmt.Errorf("unknown configuration setting %q", "streams."+key)
+	}
+	return nil
+}
+
+func (c *Config) parseClustering(key string, value interface{}) error {
+	switch key {
+	case "server.id":
+		c.Clustering.ServerID = value.(string)
+	case "namespace":
+		c.Clustering.Namespace = value.(string)
+	case "raft.snapshots":
+		c.Clustering.RaftSnapshots = value.(int)
+	case "raft.snapshot.threshold":
+		c.Clustering.RaftSnapshotThreshold = uint64(value.(int))
+	case "raft.cache.size":
+		c.Clustering.RaftCacheSize = value.(int)
+	case "raft.bootstrap.seed":
+		c.Clustering.RaftBootstrapSeed = value.(bool)
+	case "raft.bootstrap.peers":
+		peers, err := parseStringSlice(value)
+		if err != nil {
+			return errConfig("clustering.raft.bootstrap.peers", err)
+		}
+		c.Clustering.RaftBootstrapPeers = peers
+	case "replica.max.lag.time":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("clustering.replica.max.lag.time", err)
+		}
+		c.Clustering.ReplicaMaxLagTime = d
+	case "replica.max.leader.timeout":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("clustering.replica.max.leader.timeout", err)
+		}
+		c.Clustering.ReplicaMaxLeaderTimeout = d
+	case "replica.max.idle.wait":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("clustering.replica.max.idle.wait", err)
+		}
+		c.Clustering.ReplicaMaxIdleWait = d
+	case "replica.fetch.timeout":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("clustering.replica.fetch.timeout", err)
+		}
+		c.Clustering.ReplicaFetchTimeout = d
+	case "min.insync.replicas":
+		c.Clustering.MinISR = value.(int)
+	case "server.cert.enabled":
+		c.Clustering.ServerCert.Enabled = value.(bool)
+	case "server.cert.ca.lifetime":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("clustering.server.cert.ca.lifetime", err)
+		}
+		c.Clustering.ServerCert.CALifetime = d
+	case "server.cert.leaf.lifetime":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("clustering.server.cert.leaf.lifetime", err)
+		}
+		c.Clustering.ServerCert.LeafLifetime = d
+	case "server.cert.rotate.before":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("clustering.server.cert.rotate.before", err)
+		}
+		c.Clustering.ServerCert.RotateBefore = d
+	default:
+		return fmt.Errorf("unknown configuration setting %q", "clustering."+key)
+	}
+	return nil
+}
+
+func (c *Config) parseActivityStream(key string, value interface{}) error {
+	switch key {
+	case "enabled":
+		c.ActivityStream.Enabled = value.(bool)
+	case "publish.timeout":
+		d, err := parseDuration(value)
+		if err != nil {
+			return errConfig("activitystream.publish.timeout", err)
+		}
+		c.ActivityStream.PublishTimeout = d
+	case "publish.ack.policy":
+		policy, ok := client.AckPolicy_value[strings.ToUpper(value.(string))]
+		if !ok {
+			return fmt.Errorf("invalid activitystream.publish.ack.policy %q", value)
+		}
+		c.ActivityStream.PublishAckPolicy = client.AckPolicy(policy)
+	default:
+		return fmt.Errorf("unknown configuration setting %q", "activitystream."+key)
+	}
+	return nil
+}
+
+func (c *Config) parseNATS(key string, value interface{}) error {
+	switch key {
+	case "servers":
+		servers, err := parseStringSlice(value)
+		if err != nil {
+			return errConfig("nats.servers", err)
+		}
+		c.NATS.Servers = servers
+	case "user":
+		c.NATS.User = value.(string)
+	case "password":
+		c.NATS.Password = value.(string)
+	default:
+		return fmt.Errorf("unknown configuration setting %q", "nats."+key)
+	}
+	return nil
+}
+
+func parseMap(value interface{}, parse func(key string, value interface{}) error) error {
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return fmt.Errorf("expected map, got %T", value)
+	}
+	for k, v := range m {
+		key, ok := k.(string)
+		if !ok {
+			return fmt.Errorf("expected string key, got %T", k)
+		}
+		if err := parse(strings.ToLower(key), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseHostPort(value interface{}) (HostPort, error) {
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return HostPort{}, fmt.Errorf("expected map, got %T", value)
+	}
+	var hp HostPort
+	for k, v := range m {
+		key, ok := k.(string)
+		if !ok {
+			return HostPort{}, fmt.Errorf("expected string key, got %T", k)
+		}
+		switch strings.ToLower(key) {
+		case "host":
+			hp.Host, ok = v.(string)
+			if !ok {
+				return HostPort{}, fmt.Errorf("expected string, got %T", v)
+			}
+		case "port":
+			hp.Port, ok = v.(int)
+			if !ok {
+				return HostPort{}, fmt.Errorf("expected int, got %T", v)
+			}
+		case "http2.write.scheduler":
+			raw, ok := v.(string)
+			if !ok {
+				return HostPort{}, fmt.Errorf("expected string, got %T", v)
+			}
+			scheduler, err := ParseHTTP2WriteScheduler(raw)
+			if err != nil {
+				return HostPort{}, err
+			}
+			hp.HTTP2WriteScheduler = scheduler
+		default:
+			return HostPort{}, fmt.Errorf("unknown configuration setting %q", key)
+		}
+	}
+	if hp.HTTP2WriteScheduler == "" {
+		hp.HTTP2WriteScheduler = HTTP2WriteSchedulerPriority
+	}
+	return hp, nil
+}
+
+func parseStringSlice(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected list, got %T", value)
+	}
+	slice := make([]string, len(list))
+	for i, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", v)
+		}
+		slice[i] = s
+	}
+	return slice, nil
+}
+
+// parseDuration accepts either a YAML string parseable by time.ParseDuration
+// (e.g. "30s") or a bare integer, which is interpreted as a number of
+// milliseconds.
+func parseDuration(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case string:
+		return time.ParseDuration(v)
+	case int:
+		return time.Duration(v) * time.Millisecond, nil
+	default:
+		return 0, fmt.Errorf("expected string or int, got %T", value)
+	}
+}
+
+func errConfig(setting string, err error) error {
+	return fmt.Errorf("invalid configuration setting %q: %v", setting, err)
+}
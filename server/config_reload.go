@@ -0,0 +1,383 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reconcileInterval is the fallback poll period used by ConfigWatcher to
+// catch changes that fsnotify missed, e.g. on filesystems (some network
+// mounts, certain container overlay setups) that don't deliver reliable
+// inotify events.
+const reconcileInterval = 200 * time.Millisecond
+
+// ConfigWatcher monitors the YAML file a Config was loaded from and
+// hot-applies a safe subset of settings to the running Config whenever the
+// file changes, without requiring a server restart. Editors and Kubernetes
+// ConfigMap updates commonly replace a config file by writing a new inode
+// and renaming it over the original path, so the watcher tracks the file's
+// identity (device + inode) rather than assuming the original file handle
+// stays valid.
+type ConfigWatcher struct {
+	path   string
+	config *Config
+
+	mu       sync.Mutex
+	fileInfo os.FileInfo
+
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+
+	metricsMu sync.Mutex
+	metrics   ReloadMetrics
+}
+
+// ReloadMetrics tracks the outcome of hot-reload attempts for observability.
+type ReloadMetrics struct {
+	Applied  uint64
+	Rejected uint64
+	Failed   uint64
+}
+
+// NewConfigWatcher creates a ConfigWatcher for the given Config that was
+// loaded from path. path must be non-empty; watching the built-in default
+// configuration (no file) is not supported.
+func NewConfigWatcher(config *Config, path string) (*ConfigWatcher, error) {
+	if path == "" {
+		return nil, fmt.Errorf("config watcher requires a config file path")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fsWatcher.Add(path); err != nil {
+		fsWatcher.Close()
+		return nil, err
+	}
+	return &ConfigWatcher{
+		path:     path,
+		config:   config,
+		fileInfo: info,
+		watcher:  fsWatcher,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start begins watching the config file in a background goroutine. It
+// returns immediately; call Stop to shut the watcher down.
+func (w *ConfigWatcher) Start() {
+	go w.loop()
+}
+
+// Stop terminates the watcher goroutine and releases the underlying
+// fsnotify watch.
+func (w *ConfigWatcher) Stop() {
+	close(w.done)
+	w.watcher.Close()
+}
+
+// Metrics returns a snapshot of the watcher's reload counters.
+func (w *ConfigWatcher) Metrics() ReloadMetrics {
+	w.metricsMu.Lock()
+	defer w.metricsMu.Unlock()
+	return w.metrics
+}
+
+func (w *ConfigWatcher) loop() {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+				continue
+			}
+			w.handleChange()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("config watcher: fsnotify error: %v", err)
+		case <-ticker.C:
+			w.handleChange()
+		}
+	}
+}
+
+// handleChange re-stats the watched path, rebinding the fsnotify watch if
+// the file was replaced (new inode), and reloads the configuration if the
+// file looks like it changed since the last time we read it.
+func (w *ConfigWatcher) handleChange() {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		// The file may be mid-replacement (removed then re-created); leave
+		// the existing watch in place and try again on the next event or
+		// reconcile tick.
+		return
+	}
+
+	w.mu.Lock()
+	changed := !os.SameFile(w.fileInfo, info) || info.ModTime().After(w.fileInfo.ModTime())
+	w.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if !os.SameFile(w.fileInfo, info) {
+		// The editor/ConfigMap replaced the file under us. Re-establish the
+		// watch on the new inode; the old one may no longer exist on disk.
+		w.watcher.Remove(w.path)
+		if err := w.watcher.Add(w.path); err != nil {
+			log.Printf("config watcher: failed to re-watch %s: %v", w.path, err)
+		}
+	}
+
+	w.mu.Lock()
+	w.fileInfo = info
+	w.mu.Unlock()
+
+	w.reload()
+}
+
+// immutableConfigFields and reloadableConfigFields partition every leaf
+// setting in Config (as enumerated by walkConfigFields) into settings a hot
+// reload must reject a change to, and settings it's safe to apply live. The
+// two sets are required to be exhaustive and disjoint:
+// TestConfigFieldsFullyClassified in config_reload_test.go walks Config via
+// reflection and fails if any field is missing from both, so adding a field
+// to Config without classifying it here fails that test instead of being
+// silently dropped by a reload.
+var immutableConfigFields = map[string]bool{
+	"DataDir":                          true,
+	"Host":                             true,
+	"Port":                             true,
+	"TLSKey":                           true,
+	"TLSCert":                          true,
+	"Listen.Host":                      true,
+	"Listen.Port":                      true,
+	"Clustering.ServerID":              true,
+	"Clustering.Namespace":             true,
+	"Clustering.RaftSnapshots":         true,
+	"Clustering.RaftSnapshotThreshold": true,
+	"Clustering.RaftCacheSize":         true,
+	"Clustering.RaftBootstrapSeed":     true,
+	"Clustering.RaftBootstrapPeers":    true,
+	"NATS.Servers":                     true,
+	"NATS.User":                        true,
+	"NATS.Password":                    true,
+
+	// ServerCert governs internally-managed mTLS for inter-broker and Raft
+	// traffic. There's no live wiring to start/stop the CA Rotator on a
+	// reload, so treating these as reloadable would mean a hot-reloaded
+	// change to a security-sensitive toggle silently doesn't take effect;
+	// rejecting the reload is the safe failure mode until that wiring
+	// exists.
+	"Clustering.ServerCert.Enabled":      true,
+	"Clustering.ServerCert.CALifetime":   true,
+	"Clustering.ServerCert.LeafLifetime": true,
+	"Clustering.ServerCert.RotateBefore": true,
+
+	// StateRecovery is only consulted when a partition opens its segments;
+	// changing it while partitions are already open has no well-defined
+	// effect, so a reload rejects it rather than applying it silently.
+	"Streams.StateRecovery": true,
+}
+
+var reloadableConfigFields = map[string]bool{
+	"Listen.HTTP2WriteScheduler":         true,
+	"LogLevel":                           true,
+	"LogRecovery":                        true,
+	"LogRaft":                            true,
+	"LogSilent":                          true,
+	"BatchMaxMessages":                   true,
+	"BatchMaxTime":                       true,
+	"MetadataCacheMaxAge":                true,
+	"Streams.RetentionMaxBytes":          true,
+	"Streams.RetentionMaxMessages":       true,
+	"Streams.RetentionMaxAge":            true,
+	"Streams.CleanerInterval":            true,
+	"Streams.SegmentMaxBytes":            true,
+	"Streams.SegmentMaxAge":              true,
+	"Streams.Compact":                    true,
+	"Streams.CompactMaxGoroutines":       true,
+	"Streams.RetentionPolicy":            true,
+	"Streams.CompactKey":                 true,
+	"Streams.CompactKeyExtractor":        true,
+	"Streams.CompactMinCompactionLag":    true,
+	"Clustering.ReplicaMaxLagTime":       true,
+	"Clustering.ReplicaMaxLeaderTimeout": true,
+	"Clustering.ReplicaMaxIdleWait":      true,
+	"Clustering.ReplicaFetchTimeout":     true,
+	"Clustering.MinISR":                  true,
+	"ActivityStream.Enabled":             true,
+	"ActivityStream.PublishTimeout":      true,
+	"ActivityStream.PublishAckPolicy":    true,
+}
+
+// walkConfigFields visits every leaf field of Config (recursing into nested
+// config structs such as StreamsConfig and ServerCertConfig), passing each
+// visitor the field's dot-path (e.g. "Streams.RetentionPolicy") along with
+// the corresponding field out of old and new. Unexported fields (the reload
+// mutex and handler slice) aren't config settings and are skipped.
+func walkConfigFields(old, new reflect.Value, path string, visit func(path string, old, new reflect.Value)) {
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		fieldPath := field.Name
+		if path != "" {
+			fieldPath = path + "." + field.Name
+		}
+		oldField, newField := old.Field(i), new.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			walkConfigFields(oldField, newField, fieldPath, visit)
+			continue
+		}
+		visit(fieldPath, oldField, newField)
+	}
+}
+
+// configFieldPaths returns the dot-path of every leaf field in Config. It
+// operates on the type alone (never an actual Config value, which embeds a
+// sync.Mutex that shouldn't be copied) so it's safe to call without a live
+// Config to compare against.
+func configFieldPaths() []string {
+	var paths []string
+	t := reflect.TypeOf((*Config)(nil)).Elem()
+	var walk func(t reflect.Type, path string)
+	walk = func(t reflect.Type, path string) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			fieldPath := field.Name
+			if path != "" {
+				fieldPath = path + "." + field.Name
+			}
+			if field.Type.Kind() == reflect.Struct {
+				walk(field.Type, fieldPath)
+				continue
+			}
+			paths = append(paths, fieldPath)
+		}
+	}
+	walk(t, "")
+	return paths
+}
+
+func (w *ConfigWatcher) reload() {
+	newConfig, err := NewConfig(w.path)
+	if err != nil {
+		log.Printf("config watcher: failed to parse %s: %v", w.path, err)
+		w.recordOutcome(false, true)
+		return
+	}
+
+	diff, err := diffImmutableFields(w.config, newConfig)
+	if err != nil {
+		log.Printf("config watcher: refusing reload of %s: %v", w.path, err)
+		w.recordOutcome(false, true)
+		return
+	}
+	if len(diff) > 0 {
+		log.Printf("config watcher: rejecting reload of %s, immutable settings changed: %v", w.path, diff)
+		w.recordOutcome(false, false)
+		return
+	}
+
+	old := snapshotConfig(w.config)
+
+	applyReloadableConfig(w.config, newConfig)
+
+	if err := w.config.runReloadHandlers(old, w.config); err != nil {
+		log.Printf("config watcher: reload handler rejected %s: %v", w.path, err)
+		applyReloadableConfig(w.config, old)
+		w.recordOutcome(false, true)
+		return
+	}
+
+	log.Printf("config watcher: applied reloaded configuration from %s", w.path)
+	w.recordOutcome(true, false)
+}
+
+func (w *ConfigWatcher) recordOutcome(applied, failed bool) {
+	w.metricsMu.Lock()
+	defer w.metricsMu.Unlock()
+	switch {
+	case applied:
+		w.metrics.Applied++
+	case failed:
+		w.metrics.Failed++
+	default:
+		w.metrics.Rejected++
+	}
+}
+
+// diffImmutableFields returns the dot-paths of any immutable setting whose
+// value differs between old and new. It returns an error, rather than
+// silently treating the field as reloadable or immutable, if Config has a
+// field that isn't present in either immutableConfigFields or
+// reloadableConfigFields.
+func diffImmutableFields(old, new *Config) ([]string, error) {
+	var changed, unclassified []string
+	walkConfigFields(reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem(), "", func(path string, oldField, newField reflect.Value) {
+		switch {
+		case immutableConfigFields[path]:
+			if !reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+				changed = append(changed, path)
+			}
+		case reloadableConfigFields[path]:
+			// Handled by applyReloadableConfig.
+		default:
+			unclassified = append(unclassified, path)
+		}
+	})
+	if len(unclassified) > 0 {
+		sort.Strings(unclassified)
+		return nil, fmt.Errorf("config field(s) %v are not classified as reloadable or immutable", unclassified)
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// snapshotConfig copies every setting of config into a standalone Config
+// that OnReload handlers can safely read without racing the mutex and
+// handler slice embedded in the live Config.
+func snapshotConfig(config *Config) *Config {
+	snapshot := &Config{}
+	walkConfigFields(reflect.ValueOf(snapshot).Elem(), reflect.ValueOf(config).Elem(), "", func(path string, dstField, srcField reflect.Value) {
+		dstField.Set(srcField)
+	})
+	return snapshot
+}
+
+// applyReloadableConfig copies the subset of settings a hot reload is
+// allowed to change from src into dst, leaving immutable fields and
+// bookkeeping (reload handlers, mutex) untouched.
+func applyReloadableConfig(dst, src *Config) {
+	walkConfigFields(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem(), "", func(path string, dstField, srcField reflect.Value) {
+		if !reloadableConfigFields[path] {
+			return
+		}
+		dstField.Set(srcField)
+	})
+}
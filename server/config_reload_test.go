@@ -0,0 +1,142 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTempConfig(t *testing.T, dir, contents string) string {
+	path := filepath.Join(dir, "liftbridge.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+// Ensure a reload picks up a mutable setting change and invokes registered
+// OnReload handlers.
+func TestConfigWatcherAppliesReloadableChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "liftbridge-config-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeTempConfig(t, dir, "batch.max.messages: 10\n")
+	config, err := NewConfig(path)
+	require.NoError(t, err)
+
+	watcher, err := NewConfigWatcher(config, path)
+	require.NoError(t, err)
+	defer watcher.Stop()
+
+	var gotOld, gotNew *Config
+	config.OnReload(func(old, new *Config) error {
+		gotOld, gotNew = old, new
+		return nil
+	})
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("batch.max.messages: 20\n"), 0644))
+	watcher.handleChange()
+
+	require.Equal(t, 20, config.BatchMaxMessages)
+	require.Equal(t, 10, gotOld.BatchMaxMessages)
+	require.Equal(t, 20, gotNew.BatchMaxMessages)
+	require.Equal(t, uint64(1), watcher.Metrics().Applied)
+}
+
+// Ensure a reload that changes an immutable setting is rejected and leaves
+// the running configuration untouched.
+func TestConfigWatcherRejectsImmutableChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "liftbridge-config-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeTempConfig(t, dir, "data.dir: /foo\n")
+	config, err := NewConfig(path)
+	require.NoError(t, err)
+
+	watcher, err := NewConfigWatcher(config, path)
+	require.NoError(t, err)
+	defer watcher.Stop()
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("data.dir: /bar\n"), 0644))
+	watcher.handleChange()
+
+	require.Equal(t, "/foo", config.DataDir)
+	require.Equal(t, uint64(1), watcher.Metrics().Rejected)
+}
+
+// Ensure diffImmutableFields only reports settings that are actually
+// considered immutable.
+func TestDiffImmutableFields(t *testing.T) {
+	old := NewDefaultConfig()
+	old.DataDir = "/foo"
+	old.Clustering.MinISR = 1
+
+	new := NewDefaultConfig()
+	new.DataDir = "/bar"
+	new.Clustering.MinISR = 2
+
+	diff, err := diffImmutableFields(old, new)
+	require.NoError(t, err)
+	require.Equal(t, []string{"DataDir"}, diff)
+}
+
+// Ensure diffImmutableFields doesn't misclassify a change to
+// Listen.HTTP2WriteScheduler, which is reloadable, as a change to the
+// immutable listen address.
+func TestDiffImmutableFieldsIgnoresHTTP2WriteScheduler(t *testing.T) {
+	old := NewDefaultConfig()
+	new := NewDefaultConfig()
+	new.Listen.HTTP2WriteScheduler = HTTP2WriteSchedulerRoundRobin
+
+	diff, err := diffImmutableFields(old, new)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+}
+
+// Ensure every leaf field of Config is explicitly classified as reloadable
+// or immutable in config_reload.go. This is the enforcement mechanism for
+// that requirement: adding a field to Config without adding it to
+// immutableConfigFields or reloadableConfigFields fails this test instead of
+// the field being silently dropped by a reload.
+func TestConfigFieldsFullyClassified(t *testing.T) {
+	for _, path := range configFieldPaths() {
+		immutable := immutableConfigFields[path]
+		reloadable := reloadableConfigFields[path]
+		if !immutable && !reloadable {
+			t.Errorf("config field %q is not classified as reloadable or immutable in config_reload.go", path)
+		}
+		if immutable && reloadable {
+			t.Errorf("config field %q is classified as both reloadable and immutable", path)
+		}
+	}
+
+	classified := len(immutableConfigFields) + len(reloadableConfigFields)
+	if got := len(configFieldPaths()); got != classified {
+		t.Errorf("immutableConfigFields and reloadableConfigFields together have %d entries, but Config has %d fields; one of them names a field that no longer exists", classified, got)
+	}
+}
+
+// Ensure a reload that only changes the HTTP/2 write scheduler is applied,
+// not rejected as an immutable listen-address change.
+func TestConfigWatcherAppliesHTTP2WriteSchedulerChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "liftbridge-config-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := writeTempConfig(t, dir, "listen:\n  http2.write.scheduler: priority\n")
+	config, err := NewConfig(path)
+	require.NoError(t, err)
+
+	watcher, err := NewConfigWatcher(config, path)
+	require.NoError(t, err)
+	defer watcher.Stop()
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("listen:\n  http2.write.scheduler: roundrobin\n"), 0644))
+	watcher.handleChange()
+
+	require.Equal(t, HTTP2WriteSchedulerRoundRobin, config.Listen.HTTP2WriteScheduler)
+	require.Equal(t, uint64(1), watcher.Metrics().Applied)
+}
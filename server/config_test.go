@@ -17,6 +17,7 @@ func TestNewConfigFromFile(t *testing.T) {
 
 	require.Equal(t, "localhost", config.Listen.Host)
 	require.Equal(t, 9293, config.Listen.Port)
+	require.Equal(t, HTTP2WriteSchedulerRandom, config.Listen.HTTP2WriteScheduler)
 	require.Equal(t, "0.0.0.0", config.Host)
 	require.Equal(t, 5050, config.Port)
 	require.Equal(t, uint32(5), config.LogLevel)
@@ -35,6 +36,10 @@ func TestNewConfigFromFile(t *testing.T) {
 	require.Equal(t, time.Minute, config.Streams.SegmentMaxAge)
 	require.True(t, config.Streams.Compact)
 	require.Equal(t, 2, config.Streams.CompactMaxGoroutines)
+	require.Equal(t, RetentionPolicyCompactThenDelete, config.Streams.RetentionPolicy)
+	require.Equal(t, "header:event-id", config.Streams.CompactKey)
+	require.Equal(t, time.Minute, config.Streams.CompactMinCompactionLag)
+	require.Equal(t, RecoveryModeRebuild, config.Streams.StateRecovery)
 
 	require.Equal(t, "foo", config.Clustering.ServerID)
 	require.Equal(t, "bar", config.Clustering.Namespace)
@@ -47,6 +52,10 @@ func TestNewConfigFromFile(t *testing.T) {
 	require.Equal(t, 2*time.Second, config.Clustering.ReplicaMaxIdleWait)
 	require.Equal(t, 3*time.Second, config.Clustering.ReplicaFetchTimeout)
 	require.Equal(t, 1, config.Clustering.MinISR)
+	require.True(t, config.Clustering.ServerCert.Enabled)
+	require.Equal(t, 24*time.Hour, config.Clustering.ServerCert.CALifetime)
+	require.Equal(t, time.Hour, config.Clustering.ServerCert.LeafLifetime)
+	require.Equal(t, 10*time.Minute, config.Clustering.ServerCert.RotateBefore)
 
 	require.Equal(t, true, config.ActivityStream.Enabled)
 	require.Equal(t, time.Minute, config.ActivityStream.PublishTimeout)
@@ -64,6 +73,8 @@ func TestNewConfigDefault(t *testing.T) {
 	require.Equal(t, 512, config.Clustering.RaftCacheSize)
 	require.Equal(t, "liftbridge-default", config.Clustering.Namespace)
 	require.Equal(t, 1024, config.BatchMaxMessages)
+	require.Equal(t, RecoveryModeStrict, config.Streams.StateRecovery)
+	require.Equal(t, HTTP2WriteSchedulerPriority, config.Listen.HTTP2WriteScheduler)
 }
 
 // Ensure that both config file and default configs are loaded.
@@ -106,6 +117,22 @@ func TestNewConfigTLS(t *testing.T) {
 	require.Equal(t, "./configs/certs/server.crt", config.TLSCert)
 }
 
+// Ensure the listen.http2.write.scheduler setting is parsed, defaulted, and
+// validated.
+func TestNewConfigHTTP2WriteScheduler(t *testing.T) {
+	config, err := NewConfig("configs/http2-scheduler.yaml")
+	require.NoError(t, err)
+	require.Equal(t, HTTP2WriteSchedulerRoundRobin, config.Listen.HTTP2WriteScheduler)
+
+	// Defaults to the priority scheduler when unset.
+	config, err = NewConfig("configs/simple.yaml")
+	require.NoError(t, err)
+	require.Equal(t, HTTP2WriteSchedulerPriority, config.Listen.HTTP2WriteScheduler)
+
+	_, err = NewConfig("configs/http2-scheduler-invalid.yaml")
+	require.Error(t, err)
+}
+
 // Ensure error is raised when given config file not found.
 func TestNewConfigFileNotFound(t *testing.T) {
 	_, err := NewConfig("somefile.yaml")
@@ -131,17 +158,21 @@ func TestParseCustomStreamConfig(t *testing.T) {
 	// Given custom stream config
 	// duration configuration is in millisecond
 	customStreamConfig := &proto.CustomStreamConfig{
-		SegmentMaxBytes:      1024,
-		SegmentMaxAge:        1000000,
-		RetentionMaxBytes:    2048,
-		RetentionMaxMessages: 1000,
-		RetentionMaxAge:      1000000,
-		CleanerInterval:      1000000,
-		CompactMaxGoroutines: 10,
+		SegmentMaxBytes:         1024,
+		SegmentMaxAge:           1000000,
+		RetentionMaxBytes:       2048,
+		RetentionMaxMessages:    1000,
+		RetentionMaxAge:         1000000,
+		CleanerInterval:         1000000,
+		CompactMaxGoroutines:    10,
+		RetentionPolicy:         "compact",
+		CompactKey:              "subject-token:0",
+		CompactMinCompactionLag: 60000,
 	}
 	streamConfig := StreamsConfig{}
 
-	streamConfig.ParseCustomStreamConfig(customStreamConfig)
+	err := streamConfig.ParseCustomStreamConfig(customStreamConfig)
+	require.NoError(t, err)
 
 	s, _ := time.ParseDuration("1000s")
 
@@ -153,7 +184,21 @@ func TestParseCustomStreamConfig(t *testing.T) {
 	require.Equal(t, s, streamConfig.RetentionMaxAge)
 	require.Equal(t, s, streamConfig.CleanerInterval)
 	require.Equal(t, 10, streamConfig.CompactMaxGoroutines)
+	require.Equal(t, RetentionPolicyCompact, streamConfig.RetentionPolicy)
+	require.Equal(t, "subject-token:0", streamConfig.CompactKey)
+	require.NotNil(t, streamConfig.CompactKeyExtractor)
+	require.Equal(t, time.Minute, streamConfig.CompactMinCompactionLag)
+}
 
+// Ensure an invalid RetentionPolicy or CompactKey in a custom stream config
+// is rejected rather than silently ignored.
+func TestParseCustomStreamConfigInvalid(t *testing.T) {
+	streamConfig := StreamsConfig{}
+	err := streamConfig.ParseCustomStreamConfig(&proto.CustomStreamConfig{RetentionPolicy: "bogus"})
+	require.Error(t, err)
+
+	err = streamConfig.ParseCustomStreamConfig(&proto.CustomStreamConfig{CompactKey: "bogus"})
+	require.Error(t, err)
 }
 
 // Ensure default stream configs are always present,
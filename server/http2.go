@@ -0,0 +1,59 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+// HTTP2WriteScheduler selects the frame write scheduler used by the gRPC
+// API server's HTTP/2 transport. The default priority scheduler honors
+// stream dependencies/weights, but under many concurrent streams a single
+// slow reader can stall writes to unrelated streams; Subscribe is
+// particularly exposed to this since one client's backpressure shouldn't
+// head-of-line-block other subscribers.
+type HTTP2WriteScheduler string
+
+const (
+	// HTTP2WriteSchedulerPriority is Go's default HTTP/2 priority
+	// scheduler, which honors RFC 7540 stream priorities.
+	HTTP2WriteSchedulerPriority HTTP2WriteScheduler = "priority"
+	// HTTP2WriteSchedulerRandom picks a ready stream at random on every
+	// write, which trades away priority semantics for fairness under load.
+	HTTP2WriteSchedulerRandom HTTP2WriteScheduler = "random"
+	// HTTP2WriteSchedulerRoundRobin cycles through ready streams in the
+	// order they became ready, giving every stream an equal share of
+	// writes regardless of priority.
+	HTTP2WriteSchedulerRoundRobin HTTP2WriteScheduler = "roundrobin"
+)
+
+// ParseHTTP2WriteScheduler parses the `listen.http2.write.scheduler`
+// setting. An empty string defaults to HTTP2WriteSchedulerPriority.
+func ParseHTTP2WriteScheduler(value string) (HTTP2WriteScheduler, error) {
+	switch s := HTTP2WriteScheduler(value); s {
+	case "":
+		return HTTP2WriteSchedulerPriority, nil
+	case HTTP2WriteSchedulerPriority, HTTP2WriteSchedulerRandom, HTTP2WriteSchedulerRoundRobin:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown http2 write scheduler %q", value)
+	}
+}
+
+// configureHTTP2WriteScheduler configures srv's HTTP/2 transport to use the
+// write scheduler selected by scheduler.
+func configureHTTP2WriteScheduler(srv *http.Server, scheduler HTTP2WriteScheduler) error {
+	h2Server := &http2.Server{}
+	switch scheduler {
+	case "", HTTP2WriteSchedulerPriority:
+		h2Server.NewWriteScheduler = func() http2.WriteScheduler { return http2.NewPriorityWriteScheduler(nil) }
+	case HTTP2WriteSchedulerRandom:
+		h2Server.NewWriteScheduler = func() http2.WriteScheduler { return http2.NewRandomWriteScheduler() }
+	case HTTP2WriteSchedulerRoundRobin:
+		h2Server.NewWriteScheduler = func() http2.WriteScheduler { return newRoundRobinWriteScheduler() }
+	default:
+		return fmt.Errorf("unknown http2 write scheduler %q", scheduler)
+	}
+	return http2.ConfigureServer(srv, h2Server)
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"golang.org/x/net/http2"
+)
+
+// roundRobinWriteScheduler is a http2.WriteScheduler that ignores stream
+// priority entirely and instead cycles through streams with pending writes
+// in the order they became ready, giving each an equal share of the
+// connection. It's modeled on the stock random scheduler in
+// golang.org/x/net/http2, swapping the random pick for FIFO-by-stream
+// ordering. Connection-level control frames (stream ID 0: SETTINGS,
+// WINDOW_UPDATE, PING, GOAWAY) are drained ahead of the rotation, the same
+// way the stock schedulers treat them, so they can't be starved behind an
+// arbitrarily large number of data streams.
+type roundRobinWriteScheduler struct {
+	// control holds pending connection-level (stream 0) writes.
+	control []http2.FrameWriteRequest
+	// data holds the per-stream FIFOs being rotated. The bookkeeping lives
+	// in its own type, rotation, so it can be unit tested directly:
+	// http2.FrameWriteRequest has no exported fields or constructor, so a
+	// test can't build one with a chosen stream ID to exercise rotation
+	// through Push/Pop themselves.
+	data rotation
+}
+
+func newRoundRobinWriteScheduler() http2.WriteScheduler {
+	return &roundRobinWriteScheduler{data: newRotation()}
+}
+
+func (s *roundRobinWriteScheduler) OpenStream(streamID uint32, options http2.OpenStreamOptions) {
+	// Nothing to do: queues are created lazily in Push.
+}
+
+func (s *roundRobinWriteScheduler) CloseStream(streamID uint32) {
+	s.data.close(streamID)
+}
+
+func (s *roundRobinWriteScheduler) AdjustStream(streamID uint32, priority http2.PriorityParam) {
+	// Priority is intentionally ignored; that's the point of this
+	// scheduler.
+}
+
+func (s *roundRobinWriteScheduler) Push(wr http2.FrameWriteRequest) {
+	id := wr.StreamID()
+	if id == 0 {
+		s.control = append(s.control, wr)
+		return
+	}
+	s.data.push(id, wr)
+}
+
+func (s *roundRobinWriteScheduler) Pop() (http2.FrameWriteRequest, bool) {
+	if len(s.control) > 0 {
+		wr := s.control[0]
+		s.control = s.control[1:]
+		return wr, true
+	}
+	item, ok := s.data.pop()
+	if !ok {
+		return http2.FrameWriteRequest{}, false
+	}
+	return item.(http2.FrameWriteRequest), true
+}
+
+// rotation implements round-robin FIFO-by-stream-ID bookkeeping over
+// arbitrary items, keyed only by the stream ID each item belongs to. It's
+// deliberately ignorant of http2.FrameWriteRequest so it can be tested with
+// plain values.
+type rotation struct {
+	order  []uint32
+	queues map[uint32][]interface{}
+}
+
+func newRotation() rotation {
+	return rotation{queues: make(map[uint32][]interface{})}
+}
+
+func (r *rotation) push(id uint32, item interface{}) {
+	if _, ok := r.queues[id]; !ok {
+		r.order = append(r.order, id)
+	}
+	r.queues[id] = append(r.queues[id], item)
+}
+
+func (r *rotation) pop() (interface{}, bool) {
+	for len(r.order) > 0 {
+		id := r.order[0]
+		queue := r.queues[id]
+		if len(queue) == 0 {
+			// Stream had its queue drained without close being called;
+			// drop it from the rotation until it's pushed to again.
+			r.order = r.order[1:]
+			delete(r.queues, id)
+			continue
+		}
+		item := queue[0]
+		r.queues[id] = queue[1:]
+		// Rotate this stream to the back so the next pop serves a
+		// different one.
+		r.order = append(r.order[1:], id)
+		if len(r.queues[id]) == 0 {
+			delete(r.queues, id)
+			r.order = r.order[:len(r.order)-1]
+		}
+		return item, true
+	}
+	return nil, false
+}
+
+func (r *rotation) close(id uint32) {
+	delete(r.queues, id)
+	for i, existing := range r.order {
+		if existing == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Ensure ParseHTTP2WriteScheduler accepts the documented values, defaults to
+// priority, and rejects anything else.
+func TestParseHTTP2WriteScheduler(t *testing.T) {
+	scheduler, err := ParseHTTP2WriteScheduler("")
+	require.NoError(t, err)
+	require.Equal(t, HTTP2WriteSchedulerPriority, scheduler)
+
+	for _, valid := range []string{"priority", "random", "roundrobin"} {
+		scheduler, err := ParseHTTP2WriteScheduler(valid)
+		require.NoError(t, err)
+		require.Equal(t, HTTP2WriteScheduler(valid), scheduler)
+	}
+
+	_, err = ParseHTTP2WriteScheduler("bogus")
+	require.Error(t, err)
+}
+
+// Ensure configureHTTP2WriteScheduler wires a scheduler constructor for
+// every supported option and rejects unknown ones.
+func TestConfigureHTTP2WriteScheduler(t *testing.T) {
+	for _, scheduler := range []HTTP2WriteScheduler{
+		HTTP2WriteSchedulerPriority,
+		HTTP2WriteSchedulerRandom,
+		HTTP2WriteSchedulerRoundRobin,
+	} {
+		err := configureHTTP2WriteScheduler(&http.Server{}, scheduler)
+		require.NoError(t, err)
+	}
+
+	err := configureHTTP2WriteScheduler(&http.Server{}, HTTP2WriteScheduler("bogus"))
+	require.Error(t, err)
+}
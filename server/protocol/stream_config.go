@@ -0,0 +1,33 @@
+// Package protocol contains types shared between the Liftbridge server and
+// its metadata/replication wire formats.
+package protocol
+
+// CustomStreamConfig carries the per-stream configuration overrides a client
+// can attach when creating a stream. Zero values mean "use the server
+// default" for every field except CompactEnabled, which is tri-state
+// (0 = unset, 1 = true, 2 = false) since bool zero values can't distinguish
+// "not set" from "explicitly disabled".
+type CustomStreamConfig struct {
+	RetentionMaxBytes    int64
+	RetentionMaxMessages int64
+	RetentionMaxAge      int64
+	CleanerInterval      int64
+	SegmentMaxBytes      int64
+	SegmentMaxAge        int64
+	CompactEnabled       int32
+	CompactMaxGoroutines int32
+
+	// RetentionPolicy selects the retention strategy ("delete", "compact",
+	// or "compact-then-delete"). Empty keeps the server default; when no
+	// policy is set there either, the legacy CompactEnabled tri-state (or
+	// the server's compact setting) decides.
+	RetentionPolicy string
+	// CompactKey selects how the compactor derives the dedup key for a
+	// record, e.g. "message-key", "header:<name>", or "subject-token:<n>".
+	// Empty keeps the server default, which is "message-key" unless the
+	// server configures another key.
+	CompactKey string
+	// CompactMinCompactionLag is the minimum age, in milliseconds, a record
+	// must have before it's eligible to be compacted away.
+	CompactMinCompactionLag int64
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"time"
+)
+
+// RecoveryMode selects how a partition reconciles its persisted state
+// snapshot with what's actually on disk when the two disagree (e.g. after an
+// unclean shutdown).
+type RecoveryMode string
+
+const (
+	// RecoveryModeStrict treats a checksum/length mismatch between the
+	// persisted state snapshot and the on-disk segments as fatal. This is
+	// the traditional, conservative behavior.
+	RecoveryModeStrict RecoveryMode = "strict"
+	// RecoveryModeRebuild discards the stale state snapshot and fully
+	// re-scans every message block to reconstruct the sequence/byte
+	// counters from scratch.
+	RecoveryModeRebuild RecoveryMode = "rebuild"
+	// RecoveryModeTruncateTail re-reads only the last segment and adjusts
+	// the tracking state incrementally, which is cheaper than a full
+	// rebuild but only valid when per-key tracking isn't enabled.
+	RecoveryModeTruncateTail RecoveryMode = "truncate-tail"
+)
+
+// ParseRecoveryMode parses the `streams.state.recovery` setting.
+func ParseRecoveryMode(value string) (RecoveryMode, error) {
+	switch m := RecoveryMode(value); m {
+	case RecoveryModeStrict, RecoveryModeRebuild, RecoveryModeTruncateTail:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown state recovery mode %q", value)
+	}
+}
+
+// RecoveryState is the set of counters a partition tracks alongside its log
+// segments: the persisted snapshot checkpoints it, and recovery reconciles
+// it with what's actually on disk.
+type RecoveryState struct {
+	Checksum    uint32
+	Length      int64
+	HighestSeq  uint64
+	NumMessages int64
+}
+
+// PartitionScanner is what a partition's log exposes to the recovery path so
+// it can detect and repair a stale state snapshot without the recovery
+// logic needing to know about segment file formats.
+type PartitionScanner interface {
+	// Snapshot returns the state snapshot as persisted on disk.
+	Snapshot() (RecoveryState, error)
+	// OnDisk returns the checksum and byte length of what's actually
+	// present in the log's segments right now.
+	OnDisk() (checksum uint32, length int64, err error)
+	// ScanAll re-scans every message block across all segments and
+	// reconstructs the full RecoveryState.
+	ScanAll() (RecoveryState, error)
+	// ScanTail re-scans only the last segment and returns the RecoveryState
+	// for the whole partition, adjusting the persisted snapshot's counters
+	// incrementally for the tail segment's actual contents.
+	ScanTail() (RecoveryState, error)
+	// PerKeyTrackingEnabled reports whether the partition tracks per-subject
+	// or max-messages-per-key state, which a partial tail scan can't
+	// reconstruct correctly.
+	PerKeyTrackingEnabled() bool
+}
+
+// RecoveryMetrics describes the outcome of a single recovery attempt.
+type RecoveryMetrics struct {
+	Mode     RecoveryMode
+	Rebuilt  bool
+	Duration time.Duration
+}
+
+// Recover reconciles a partition's persisted state snapshot with its
+// on-disk segments according to mode. If the snapshot matches what's on
+// disk, scanner isn't asked to do any re-scanning work.
+func Recover(mode RecoveryMode, scanner PartitionScanner) (RecoveryState, RecoveryMetrics, error) {
+	start := time.Now()
+	metrics := RecoveryMetrics{Mode: mode}
+
+	snapshot, err := scanner.Snapshot()
+	if err != nil {
+		return RecoveryState{}, metrics, err
+	}
+	checksum, length, err := scanner.OnDisk()
+	if err != nil {
+		return RecoveryState{}, metrics, err
+	}
+	if checksum == snapshot.Checksum && length == snapshot.Length {
+		metrics.Duration = time.Since(start)
+		return snapshot, metrics, nil
+	}
+
+	// Per-key tracking can't be reconstructed correctly from a partial tail
+	// scan, so a truncate-tail recovery is upgraded to a full rebuild. Strict
+	// mode is left as is and still fails on the mismatch.
+	effectiveMode := mode
+	if mode == RecoveryModeTruncateTail && scanner.PerKeyTrackingEnabled() {
+		effectiveMode = RecoveryModeRebuild
+	}
+	metrics.Mode = effectiveMode
+
+	switch effectiveMode {
+	case RecoveryModeStrict:
+		metrics.Duration = time.Since(start)
+		return RecoveryState{}, metrics, fmt.Errorf(
+			"partition state mismatch: checksum=%d want=%d, length=%d want=%d",
+			checksum, snapshot.Checksum, length, snapshot.Length)
+	case RecoveryModeRebuild:
+		state, err := scanner.ScanAll()
+		metrics.Rebuilt = err == nil
+		metrics.Duration = time.Since(start)
+		return state, metrics, err
+	case RecoveryModeTruncateTail:
+		state, err := scanner.ScanTail()
+		metrics.Rebuilt = err == nil
+		metrics.Duration = time.Since(start)
+		return state, metrics, err
+	default:
+		metrics.Duration = time.Since(start)
+		return RecoveryState{}, metrics, fmt.Errorf("unknown state recovery mode %q", mode)
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeScanner simulates a partition whose on-disk segments have drifted
+// from the persisted snapshot, e.g. because the last segment was truncated
+// by an unclean shutdown.
+type fakeScanner struct {
+	snapshot      RecoveryState
+	onDiskSum     uint32
+	onDiskLen     int64
+	scanAllState  RecoveryState
+	scanAllErr    error
+	scanTailState RecoveryState
+	scanTailErr   error
+	perKeyEnabled bool
+}
+
+func (f *fakeScanner) Snapshot() (RecoveryState, error) { return f.snapshot, nil }
+func (f *fakeScanner) OnDisk() (uint32, int64, error)   { return f.onDiskSum, f.onDiskLen, nil }
+func (f *fakeScanner) ScanAll() (RecoveryState, error)  { return f.scanAllState, f.scanAllErr }
+func (f *fakeScanner) ScanTail() (RecoveryState, error) { return f.scanTailState, f.scanTailErr }
+func (f *fakeScanner) PerKeyTrackingEnabled() bool      { return f.perKeyEnabled }
+
+func corruptedScanner() *fakeScanner {
+	return &fakeScanner{
+		snapshot:      RecoveryState{Checksum: 1, Length: 100, HighestSeq: 10},
+		onDiskSum:     2, // corrupted tail: on-disk checksum no longer matches
+		onDiskLen:     80,
+		scanAllState:  RecoveryState{Checksum: 2, Length: 80, HighestSeq: 8, NumMessages: 8},
+		scanTailState: RecoveryState{Checksum: 2, Length: 80, HighestSeq: 8, NumMessages: 8},
+	}
+}
+
+// Ensure strict mode fails fast on a mismatch rather than attempting repair.
+func TestRecoverStrictModeFailsOnMismatch(t *testing.T) {
+	scanner := corruptedScanner()
+	_, metrics, err := Recover(RecoveryModeStrict, scanner)
+	require.Error(t, err)
+	require.Equal(t, RecoveryModeStrict, metrics.Mode)
+	require.False(t, metrics.Rebuilt)
+}
+
+// Ensure rebuild mode fully re-scans and reconstructs the state.
+func TestRecoverRebuildMode(t *testing.T) {
+	scanner := corruptedScanner()
+	state, metrics, err := Recover(RecoveryModeRebuild, scanner)
+	require.NoError(t, err)
+	require.True(t, metrics.Rebuilt)
+	require.Equal(t, uint64(8), state.HighestSeq)
+}
+
+// Ensure truncate-tail mode only re-scans the last segment when per-key
+// tracking is disabled.
+func TestRecoverTruncateTailMode(t *testing.T) {
+	scanner := corruptedScanner()
+	state, metrics, err := Recover(RecoveryModeTruncateTail, scanner)
+	require.NoError(t, err)
+	require.Equal(t, RecoveryModeTruncateTail, metrics.Mode)
+	require.Equal(t, uint64(8), state.HighestSeq)
+}
+
+// Ensure truncate-tail mode is upgraded to a full rebuild when per-subject
+// or max-messages-per-key tracking is enabled, since a partial tail scan
+// can't reconstruct per-key counts correctly.
+func TestRecoverTruncateTailForcesRebuildWithPerKeyTracking(t *testing.T) {
+	scanner := corruptedScanner()
+	scanner.perKeyEnabled = true
+	_, metrics, err := Recover(RecoveryModeTruncateTail, scanner)
+	require.NoError(t, err)
+	require.Equal(t, RecoveryModeRebuild, metrics.Mode)
+}
+
+// Ensure a snapshot that already matches what's on disk skips recovery
+// entirely, regardless of mode.
+func TestRecoverNoMismatchIsNoop(t *testing.T) {
+	scanner := &fakeScanner{
+		snapshot:  RecoveryState{Checksum: 1, Length: 100, HighestSeq: 10},
+		onDiskSum: 1,
+		onDiskLen: 100,
+	}
+	state, metrics, err := Recover(RecoveryModeStrict, scanner)
+	require.NoError(t, err)
+	require.False(t, metrics.Rebuilt)
+	require.Equal(t, uint64(10), state.HighestSeq)
+}
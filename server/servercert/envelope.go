@@ -0,0 +1,46 @@
+package servercert
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// encodeCAEnvelope packs a CA's certificate and private key DER bytes into
+// a single length-prefixed blob for storage in the Raft FSM.
+func encodeCAEnvelope(certDER, keyDER []byte) []byte {
+	buf := make([]byte, 0, 8+len(certDER)+len(keyDER))
+	buf = appendUint32(buf, uint32(len(certDER)))
+	buf = append(buf, certDER...)
+	buf = appendUint32(buf, uint32(len(keyDER)))
+	buf = append(buf, keyDER...)
+	return buf
+}
+
+func decodeCAEnvelope(data []byte) (certDER, keyDER []byte, err error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("servercert: truncated CA envelope")
+	}
+	certLen := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	if uint32(len(data)) < certLen {
+		return nil, nil, fmt.Errorf("servercert: truncated CA envelope")
+	}
+	certDER, data = data[:certLen], data[certLen:]
+
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("servercert: truncated CA envelope")
+	}
+	keyLen := binary.BigEndian.Uint32(data)
+	data = data[4:]
+	if uint32(len(data)) < keyLen {
+		return nil, nil, fmt.Errorf("servercert: truncated CA envelope")
+	}
+	keyDER = data[:keyLen]
+	return certDER, keyDER, nil
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	return append(buf, tmp[:]...)
+}
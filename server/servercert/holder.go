@@ -0,0 +1,88 @@
+package servercert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"sync/atomic"
+)
+
+// errNoCertificate is returned when a Holder is used before Update has ever
+// been called.
+var errNoCertificate = errors.New("servercert: no certificate installed")
+
+// Holder holds the latest leaf certificate and CA pool for a peer and
+// exposes them through the callbacks crypto/tls calls on every handshake
+// (GetCertificate, GetClientCertificate), so that updating a Holder rotates
+// the certificate in place: in-flight connections are unaffected, and new
+// handshakes pick up the new leaf immediately.
+type Holder struct {
+	state atomic.Value // holds *holderState
+}
+
+type holderState struct {
+	leaf *tls.Certificate
+	pool *x509.CertPool
+}
+
+// NewHolder creates an empty Holder. It must be populated with Update before
+// GetCertificate/GetClientCertificate are called.
+func NewHolder() *Holder {
+	return &Holder{}
+}
+
+// Update installs a new leaf certificate and CA, atomically replacing
+// whatever was previously held.
+func (h *Holder) Update(leaf tls.Certificate, ca *CA) {
+	h.state.Store(&holderState{
+		leaf: &leaf,
+		pool: ca.CertPool(),
+	})
+}
+
+// GetCertificate implements tls.Config.GetCertificate, always returning the
+// most recently installed leaf certificate.
+func (h *Holder) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s, err := h.current()
+	if err != nil {
+		return nil, err
+	}
+	return s.leaf, nil
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate, always
+// returning the most recently installed leaf certificate.
+func (h *Holder) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	s, err := h.current()
+	if err != nil {
+		return nil, err
+	}
+	return s.leaf, nil
+}
+
+// TLSConfig returns a *tls.Config wired to always use this Holder's current
+// leaf certificate and CA pool, suitable for the Raft transport and the
+// inter-broker replication client/server. Since RootCAs/ClientCAs are
+// snapshotted at call time, TLSConfig should be called again (or the
+// VerifyPeerCertificate hook used) after a CA rotation.
+func (h *Holder) TLSConfig() (*tls.Config, error) {
+	s, err := h.current()
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		GetCertificate:       h.GetCertificate,
+		GetClientCertificate: h.GetClientCertificate,
+		ClientAuth:           tls.RequireAndVerifyClientCert,
+		RootCAs:              s.pool,
+		ClientCAs:            s.pool,
+	}, nil
+}
+
+func (h *Holder) current() (*holderState, error) {
+	v := h.state.Load()
+	if v == nil {
+		return nil, errNoCertificate
+	}
+	return v.(*holderState), nil
+}
@@ -0,0 +1,99 @@
+package servercert
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+// Rotator keeps a Holder populated with a leaf certificate for this peer,
+// re-issuing it RotateBefore its expiry. Issuance itself always goes
+// through the current cluster leader (see Issuer); a Rotator running on a
+// follower just asks the leader for a new leaf and installs whatever comes
+// back into its Holder.
+type Rotator struct {
+	peerID       string
+	leafLifetime time.Duration
+	rotateBefore time.Duration
+	holder       *Holder
+	issuer       Issuer
+
+	stop chan struct{}
+}
+
+// Issuer mints (or requests) a new leaf certificate for peerID. On the
+// leader this is backed directly by CA.Issue; on a follower it's backed by
+// an RPC to the leader, which replicates the result through Raft before
+// returning it so every node converges on the same leaf for a given peer.
+type Issuer interface {
+	IssueLeaf(peerID string, lifetime time.Duration) (tls.Certificate, *CA, error)
+}
+
+// NewRotator creates a Rotator that keeps holder populated with a leaf for
+// peerID, minted via issuer, refreshed rotateBefore its expiry.
+func NewRotator(peerID string, leafLifetime, rotateBefore time.Duration, issuer Issuer, holder *Holder) *Rotator {
+	return &Rotator{
+		peerID:       peerID,
+		leafLifetime: leafLifetime,
+		rotateBefore: rotateBefore,
+		holder:       holder,
+		issuer:       issuer,
+		stop:         make(chan struct{}),
+	}
+}
+
+// Start issues an initial leaf synchronously, then rotates it in the
+// background RotateBefore its expiry for as long as the Rotator runs.
+func (r *Rotator) Start() error {
+	next, err := r.rotate()
+	if err != nil {
+		return err
+	}
+	go r.loop(next)
+	return nil
+}
+
+// Stop halts background rotation. It doesn't clear the Holder, so the last
+// issued leaf remains in use.
+func (r *Rotator) Stop() {
+	close(r.stop)
+}
+
+func (r *Rotator) loop(next time.Duration) {
+	timer := time.NewTimer(next)
+	defer timer.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-timer.C:
+			wait, err := r.rotate()
+			if err != nil {
+				// Back off and try again rather than leaving the peer on a
+				// leaf that's approaching expiry; the leader may just be
+				// mid-election.
+				wait = r.rotateBefore / 4
+				if wait <= 0 {
+					wait = time.Second
+				}
+			}
+			timer.Reset(wait)
+		}
+	}
+}
+
+// rotate issues a fresh leaf and returns how long to wait before the next
+// rotation.
+func (r *Rotator) rotate() (time.Duration, error) {
+	leaf, ca, err := r.issuer.IssueLeaf(r.peerID, r.leafLifetime)
+	if err != nil {
+		return 0, err
+	}
+	r.holder.Update(leaf, ca)
+
+	untilExpiry := time.Until(leaf.Leaf.NotAfter)
+	wait := untilExpiry - r.rotateBefore
+	if wait <= 0 {
+		wait = time.Second
+	}
+	return wait, nil
+}
@@ -0,0 +1,179 @@
+package servercert
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIssuer struct {
+	ca       *CA
+	attempts int32
+}
+
+func (f *fakeIssuer) IssueLeaf(peerID string, lifetime time.Duration) (tls.Certificate, *CA, error) {
+	atomic.AddInt32(&f.attempts, 1)
+	leaf, err := f.ca.Issue(peerID, lifetime)
+	return leaf, f.ca, err
+}
+
+// Ensure Start issues an initial leaf synchronously and installs it into
+// the Holder before returning.
+func TestRotatorStartIssuesInitialLeaf(t *testing.T) {
+	ca, err := GenerateCA("test-cluster", time.Hour)
+	require.NoError(t, err)
+
+	issuer := &fakeIssuer{ca: ca}
+	holder := NewHolder()
+	rotator := NewRotator("broker-1", time.Minute, 30*time.Second, issuer, holder)
+
+	require.NoError(t, rotator.Start())
+	defer rotator.Stop()
+
+	cert, err := holder.GetCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, "broker-1", cert.Leaf.Subject.CommonName)
+	require.EqualValues(t, 1, atomic.LoadInt32(&issuer.attempts))
+}
+
+// Ensure the rotator proactively re-issues a leaf before it expires, rather
+// than waiting until the old one is already invalid.
+func TestRotatorRotatesBeforeExpiry(t *testing.T) {
+	ca, err := GenerateCA("test-cluster", time.Hour)
+	require.NoError(t, err)
+
+	issuer := &fakeIssuer{ca: ca}
+	holder := NewHolder()
+	// X.509 validity times have one-second resolution, so the lifetime is
+	// whole seconds. With rotateBefore at 2s of a 3s lifetime, the first
+	// rotation fires within a second, long before the leaf expires.
+	rotator := NewRotator("broker-1", 3*time.Second, 2*time.Second, issuer, holder)
+
+	require.NoError(t, rotator.Start())
+	defer rotator.Stop()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&issuer.attempts) >= 2
+	}, 3*time.Second, 5*time.Millisecond)
+}
+
+// handoffIssuer simulates a Raft leadership change partway through a
+// Rotator's lifetime: the first `before` calls are served by the old
+// leader, every call after that by the new one. Both share the same CA, as
+// they would in the real cluster since the CA is replicated through Raft
+// rather than regenerated on election.
+type handoffIssuer struct {
+	mu        sync.Mutex
+	calls     int
+	before    int
+	oldLeader Issuer
+	newLeader Issuer
+}
+
+func (h *handoffIssuer) IssueLeaf(peerID string, lifetime time.Duration) (tls.Certificate, *CA, error) {
+	h.mu.Lock()
+	h.calls++
+	useNew := h.calls > h.before
+	h.mu.Unlock()
+	if useNew {
+		return h.newLeader.IssueLeaf(peerID, lifetime)
+	}
+	return h.oldLeader.IssueLeaf(peerID, lifetime)
+}
+
+// Ensure a Rotator keeps producing valid, verifiable leaves across a
+// simulated leader failover, where issuance switches from one Issuer to
+// another mid-rotation.
+func TestRotatorSurvivesLeaderFailover(t *testing.T) {
+	ca, err := GenerateCA("test-cluster", time.Hour)
+	require.NoError(t, err)
+
+	oldLeader := &fakeIssuer{ca: ca}
+	newLeader := &fakeIssuer{ca: ca}
+	handoff := &handoffIssuer{before: 1, oldLeader: oldLeader, newLeader: newLeader}
+
+	holder := NewHolder()
+	// Whole-second lifetime (see TestRotatorRotatesBeforeExpiry): each
+	// rotation fires within a second of the previous one.
+	rotator := NewRotator("broker-1", 3*time.Second, 2*time.Second, handoff, holder)
+
+	require.NoError(t, rotator.Start())
+	defer rotator.Stop()
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&newLeader.attempts) >= 2
+	}, 5*time.Second, 5*time.Millisecond)
+	require.GreaterOrEqual(t, atomic.LoadInt32(&oldLeader.attempts), int32(1))
+
+	cert, err := holder.GetCertificate(nil)
+	require.NoError(t, err)
+	_, err = cert.Leaf.Verify(x509VerifyOptions(ca))
+	require.NoError(t, err)
+}
+
+// Ensure certificates served by a Holder stay valid while many peers rotate
+// concurrently and their holders are read from concurrently, the way many
+// inbound connections would handshake against them under real load.
+func TestRotatorUnderConcurrentLoad(t *testing.T) {
+	ca, err := GenerateCA("test-cluster", time.Hour)
+	require.NoError(t, err)
+
+	const peers = 20
+	holders := make([]*Holder, peers)
+	rotators := make([]*Rotator, peers)
+	for i := 0; i < peers; i++ {
+		holders[i] = NewHolder()
+		issuer := &fakeIssuer{ca: ca}
+		// Whole-second lifetime (see TestRotatorRotatesBeforeExpiry): every
+		// peer rotates within a second, inside the load window below.
+		rotators[i] = NewRotator(fmt.Sprintf("broker-%d", i), 3*time.Second, 2*time.Second, issuer, holders[i])
+		require.NoError(t, rotators[i].Start())
+	}
+	defer func() {
+		for _, r := range rotators {
+			r.Stop()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, peers)
+	stop := make(chan struct{})
+	for _, h := range holders {
+		wg.Add(1)
+		go func(h *Holder) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := h.GetCertificate(nil); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(h)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent GetCertificate failed: %v", err)
+	}
+
+	opts := x509VerifyOptions(ca)
+	for _, h := range holders {
+		cert, err := h.GetCertificate(nil)
+		require.NoError(t, err)
+		_, err = cert.Leaf.Verify(opts)
+		require.NoError(t, err)
+	}
+}
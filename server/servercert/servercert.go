@@ -0,0 +1,155 @@
+// Package servercert implements an internally-managed certificate
+// authority for securing inter-broker and Raft traffic with mTLS, so
+// operators don't have to provision and rotate certificates by hand.
+//
+// A cluster-local CA is seeded once, at bootstrap, by whichever server
+// forms the cluster first. Its key material is replicated through Raft
+// (the caller is responsible for storing the serialized CA in the FSM and
+// handing it back to UnmarshalCA on every node, including
+// after a leadership change) so that any node that becomes leader can mint
+// leaf certificates for the rest of the cluster. Leaves are short-lived and
+// rotated well before they expire; a Holder exposes the always-current leaf
+// to Go's tls.Config via GetCertificate/GetClientCertificate so that
+// rotation never requires dropping an established connection.
+package servercert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// CA is a cluster-local certificate authority used to mint short-lived leaf
+// certificates for peers. Its private key is sensitive: it's generated once
+// at cluster bootstrap and from then on is only ever read from the
+// Raft-replicated metadata that seeded it.
+type CA struct {
+	cert    *x509.Certificate
+	certDER []byte
+	key     *ecdsa.PrivateKey
+}
+
+// GenerateCA creates a new self-signed CA valid for lifetime. This is only
+// ever called once per cluster, by the server that bootstraps it; every
+// other node obtains the CA via UnmarshalCA against the Raft-replicated
+// copy.
+func GenerateCA(clusterID string, lifetime time.Duration) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("servercert: generate CA key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("liftbridge-cluster-ca-%s", clusterID)},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(lifetime),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("servercert: create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{cert: cert, certDER: der, key: key}, nil
+}
+
+// Marshal serializes the CA (certificate and private key) so it can be
+// stored in the Raft FSM and replicated to every node in the cluster.
+func (ca *CA) Marshal() ([]byte, error) {
+	keyDER, err := x509.MarshalECPrivateKey(ca.key)
+	if err != nil {
+		return nil, err
+	}
+	return encodeCAEnvelope(ca.certDER, keyDER), nil
+}
+
+// UnmarshalCA reconstructs a CA from the bytes produced by Marshal. Every
+// node calls this against the copy of the CA replicated through Raft so
+// that whichever node is leader at the time can mint leaf certificates.
+func UnmarshalCA(data []byte) (*CA, error) {
+	certDER, keyDER, err := decodeCAEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, fmt.Errorf("servercert: parse CA certificate: %w", err)
+	}
+	key, err := x509.ParseECPrivateKey(keyDER)
+	if err != nil {
+		return nil, fmt.Errorf("servercert: parse CA key: %w", err)
+	}
+	return &CA{cert: cert, certDER: certDER, key: key}, nil
+}
+
+// CertPool returns an x509.CertPool containing just this CA, suitable for
+// verifying peer leaf certificates it issued.
+func (ca *CA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// Expiry returns when the CA certificate itself expires.
+func (ca *CA) Expiry() time.Time {
+	return ca.cert.NotAfter
+}
+
+// Issue mints a new leaf certificate for peerID, valid for lifetime. The
+// resulting certificate chains to this CA and is a valid server and client
+// certificate, since inter-broker/Raft connections authenticate in both
+// directions.
+func (ca *CA) Issue(peerID string, lifetime time.Duration) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("servercert: generate leaf key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: peerID},
+		DNSNames:     []string{peerID},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(lifetime),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("servercert: issue leaf for %q: %w", peerID, err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der, ca.certDER},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), 128)
+	return rand.Int(rand.Reader, limit)
+}
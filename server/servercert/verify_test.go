@@ -0,0 +1,10 @@
+package servercert
+
+import "crypto/x509"
+
+func x509VerifyOptions(ca *CA) x509.VerifyOptions {
+	return x509.VerifyOptions{
+		Roots:     ca.CertPool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+}